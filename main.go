@@ -2,18 +2,34 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
 const (
-	apiBase = "https://bsky.social/xrpc"
+	// defaultPDS is used when --pds/BSKY_PDS aren't set, i.e. for accounts
+	// hosted on Bluesky's own PDS.
+	defaultPDS = "https://bsky.social/xrpc"
+
+	// publicAppView is queried for public reads that don't require the
+	// caller's own PDS, such as resolving a handle or fetching another
+	// account's follows.
+	publicAppView = "https://api.bsky.app/xrpc"
+
+	// defaultBatchSize is the number of records written per applyWrites call.
+	defaultBatchSize = 200
 )
 
 // API request/response types
@@ -24,9 +40,17 @@ type createSessionRequest struct {
 }
 
 type createSessionResponse struct {
-	AccessJwt string `json:"accessJwt"`
-	DID       string `json:"did"`
-	Handle    string `json:"handle"`
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	DID        string `json:"did"`
+	Handle     string `json:"handle"`
+}
+
+type refreshSessionResponse struct {
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	DID        string `json:"did"`
+	Handle     string `json:"handle"`
 }
 
 type profileView struct {
@@ -66,25 +90,242 @@ type createRecordResponse struct {
 	CID string `json:"cid"`
 }
 
+type listView struct {
+	URI  string `json:"uri"`
+	CID  string `json:"cid"`
+	Name string `json:"name"`
+}
+
+type getListsResponse struct {
+	Lists  []listView `json:"lists"`
+	Cursor string     `json:"cursor"`
+}
+
+type listItemView struct {
+	URI     string      `json:"uri"`
+	Subject profileView `json:"subject"`
+}
+
+type getListResponse struct {
+	Items  []listItemView `json:"items"`
+	Cursor string         `json:"cursor"`
+}
+
+type didDocumentService struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+type didDocument struct {
+	ID      string               `json:"id"`
+	Service []didDocumentService `json:"service"`
+}
+
+// WriteOp identifies the kind of operation a Write performs within an
+// applyWrites call.
+type WriteOp string
+
+const (
+	WriteCreate WriteOp = "create"
+	WriteUpdate WriteOp = "update"
+	WriteDelete WriteOp = "delete"
+)
+
+// Write is a single operation within a com.atproto.repo.applyWrites call.
+// RKey is required for WriteUpdate and WriteDelete, optional for WriteCreate.
+// Value is required for WriteCreate and WriteUpdate.
+type Write struct {
+	Op         WriteOp
+	Collection string
+	RKey       string
+	Value      interface{}
+}
+
+// MarshalJSON renders a Write as the tagged union applyWrites expects, e.g.
+// {"$type": "com.atproto.repo.applyWrites#create", "collection": ..., "value": ...}.
+func (w Write) MarshalJSON() ([]byte, error) {
+	switch w.Op {
+	case WriteCreate:
+		return json.Marshal(struct {
+			Type       string      `json:"$type"`
+			Collection string      `json:"collection"`
+			RKey       string      `json:"rkey,omitempty"`
+			Value      interface{} `json:"value"`
+		}{"com.atproto.repo.applyWrites#create", w.Collection, w.RKey, w.Value})
+	case WriteUpdate:
+		return json.Marshal(struct {
+			Type       string      `json:"$type"`
+			Collection string      `json:"collection"`
+			RKey       string      `json:"rkey"`
+			Value      interface{} `json:"value"`
+		}{"com.atproto.repo.applyWrites#update", w.Collection, w.RKey, w.Value})
+	case WriteDelete:
+		return json.Marshal(struct {
+			Type       string `json:"$type"`
+			Collection string `json:"collection"`
+			RKey       string `json:"rkey"`
+		}{"com.atproto.repo.applyWrites#delete", w.Collection, w.RKey})
+	default:
+		return nil, fmt.Errorf("applyWrites: unknown op %q", w.Op)
+	}
+}
+
+type applyWritesRequest struct {
+	Repo   string  `json:"repo"`
+	Writes []Write `json:"writes"`
+}
+
 type apiError struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 }
 
+// expiredTokenError marks an API error whose apiError.Error field is
+// "ExpiredToken", so doRequest can recognize it and retry after a refresh.
+type expiredTokenError struct {
+	message string
+}
+
+func (e *expiredTokenError) Error() string { return e.message }
+
+// rateLimitedError marks a 429 response, carrying how long doRequest should
+// wait before retrying.
+type rateLimitedError struct {
+	message string
+	wait    time.Duration
+}
+
+func (e *rateLimitedError) Error() string { return e.message }
+
+// transientServerError marks a 5xx response, which is safe to retry with
+// exponential backoff.
+type transientServerError struct {
+	message string
+}
+
+func (e *transientServerError) Error() string { return e.message }
+
+// RateLimitStatus reflects the most recently observed Bluesky rate-limit
+// headers so callers can report or plan around it instead of retrying blind.
+type RateLimitStatus struct {
+	Remaining int
+	Reset     time.Time
+}
+
+// maxTransientRetries caps how many times doRequest will wait out a rate
+// limit or retry a transient 5xx before giving up.
+const maxTransientRetries = 5
+
 // Client for Bluesky API
 type Client struct {
 	httpClient *http.Client
+	pdsBase    string // authenticated user's PDS; writes and own-account reads go here
 	accessJwt  string
+	refreshJwt string
 	did        string
+	rateLimit  RateLimitStatus
 }
 
-func NewClient() *Client {
+// NewClient creates a Client that authenticates against pdsBase, the
+// authenticated user's own PDS (e.g. defaultPDS for a bsky.social account,
+// or a self-hosted PDS's xrpc base URL).
+func NewClient(pdsBase string) *Client {
 	return &Client{
 		httpClient: &http.Client{Timeout: 30 * time.Second},
+		pdsBase:    pdsBase,
+	}
+}
+
+// RateLimitStatus returns the rate-limit state from the most recent response
+// that carried RateLimit-Remaining or RateLimit-Reset headers.
+func (c *Client) RateLimitStatus() RateLimitStatus {
+	return c.rateLimit
+}
+
+// DID returns the authenticated user's DID, populated after CreateSession.
+func (c *Client) DID() string {
+	return c.did
+}
+
+// ResolveHandle resolves a handle to its DID via
+// com.atproto.identity.resolveHandle against the public AppView, which can
+// resolve handles regardless of which PDS the account actually lives on.
+func (c *Client) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	endpoint := fmt.Sprintf("/com.atproto.identity.resolveHandle?handle=%s", url.QueryEscape(handle))
+
+	respBody, err := c.doRequest(ctx, publicAppView, "GET", endpoint, nil, false)
+	if err != nil {
+		return "", fmt.Errorf("resolve handle: %w", err)
+	}
+
+	var resp struct {
+		DID string `json:"did"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("parse resolve handle response: %w", err)
 	}
+
+	return resp.DID, nil
 }
 
-func (c *Client) doRequest(method, endpoint string, body interface{}, authenticated bool) ([]byte, error) {
+// ResolvePDSEndpoint discovers an account's PDS by fetching its DID document
+// (from plc.directory for did:plc, or the did:web well-known path) and
+// reading the #atproto_pds service entry. The returned URL is an xrpc base,
+// ready to pass as the base argument to a Client method.
+func (c *Client) ResolvePDSEndpoint(ctx context.Context, did string) (string, error) {
+	var docURL string
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		docURL = "https://plc.directory/" + did
+	case strings.HasPrefix(did, "did:web:"):
+		domain, err := url.QueryUnescape(strings.TrimPrefix(did, "did:web:"))
+		if err != nil {
+			return "", fmt.Errorf("resolve pds: invalid did:web identifier %q: %w", did, err)
+		}
+		docURL = "https://" + domain + "/.well-known/did.json"
+	default:
+		return "", fmt.Errorf("resolve pds: unsupported DID method: %s", did)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", docURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("resolve pds: create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("resolve pds: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("resolve pds: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("resolve pds: API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var doc didDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("resolve pds: parse DID document: %w", err)
+	}
+
+	for _, svc := range doc.Service {
+		if svc.ID == "#atproto_pds" {
+			return strings.TrimSuffix(svc.ServiceEndpoint, "/") + "/xrpc", nil
+		}
+	}
+
+	return "", fmt.Errorf("resolve pds: no #atproto_pds service found for %s", did)
+}
+
+// doRequestWithAuth performs a single HTTP round-trip against base+endpoint,
+// sending bearer as the Authorization token when non-empty. A 400/401 whose
+// apiError.Error is "ExpiredToken" is returned as an *expiredTokenError so
+// callers can decide whether to refresh and retry.
+func (c *Client) doRequestWithAuth(ctx context.Context, base, method, endpoint string, body interface{}, bearer string) ([]byte, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -94,7 +335,7 @@ func (c *Client) doRequest(method, endpoint string, body interface{}, authentica
 		reqBody = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, apiBase+endpoint, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, base+endpoint, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -102,8 +343,8 @@ func (c *Client) doRequest(method, endpoint string, body interface{}, authentica
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	if authenticated && c.accessJwt != "" {
-		req.Header.Set("Authorization", "Bearer "+c.accessJwt)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
 	}
 
 	resp, err := c.httpClient.Do(req)
@@ -112,14 +353,30 @@ func (c *Client) doRequest(method, endpoint string, body interface{}, authentica
 	}
 	defer resp.Body.Close()
 
+	c.recordRateLimit(resp.Header)
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("read response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &rateLimitedError{
+			message: fmt.Sprintf("API error (%d): rate limited", resp.StatusCode),
+			wait:    retryWait(resp.Header, c.rateLimit),
+		}
+	}
+
+	if resp.StatusCode >= 500 {
+		return nil, &transientServerError{message: fmt.Sprintf("API error (%d): %s", resp.StatusCode, string(respBody))}
+	}
+
 	if resp.StatusCode >= 400 {
 		var apiErr apiError
 		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Message != "" {
+			if (resp.StatusCode == 400 || resp.StatusCode == 401) && apiErr.Error == "ExpiredToken" {
+				return nil, &expiredTokenError{message: fmt.Sprintf("API error (%d): %s", resp.StatusCode, apiErr.Message)}
+			}
 			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, apiErr.Message)
 		}
 		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(respBody))
@@ -128,13 +385,142 @@ func (c *Client) doRequest(method, endpoint string, body interface{}, authentica
 	return respBody, nil
 }
 
-func (c *Client) CreateSession(handle, password string) error {
+// recordRateLimit updates the client's RateLimitStatus from the
+// RateLimit-Remaining and RateLimit-Reset headers, if present.
+func (c *Client) recordRateLimit(header http.Header) {
+	if remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil {
+		c.rateLimit.Remaining = remaining
+	}
+	if reset, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64); err == nil {
+		c.rateLimit.Reset = time.Unix(reset, 0)
+	}
+}
+
+// retryWait picks how long to wait before retrying a 429: the standard
+// Retry-After header if present, otherwise the RateLimit-Reset time.
+func retryWait(header http.Header, status RateLimitStatus) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if !status.Reset.IsZero() {
+		if wait := time.Until(status.Reset); wait > 0 {
+			return wait
+		}
+	}
+	return time.Second
+}
+
+// backoffWithJitter returns an exponential backoff duration for the given
+// retry attempt (0-indexed), with up to 50% jitter to avoid thundering-herd
+// retries.
+func backoffWithJitter(attempt int) time.Duration {
+	base := 500 * time.Millisecond * time.Duration(1<<attempt)
+	jitter := time.Duration(rand.Int63n(int64(base) / 2))
+	return base + jitter
+}
+
+// sleepCtx sleeps for d, returning early with ctx.Err() if ctx is canceled.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// doRequest performs an authenticated (or anonymous) request against
+// base+endpoint. It transparently refreshes the session and retries exactly
+// once if the access token has expired. It waits out rate limiting (429, or
+// RateLimit-Remaining at 0) until Retry-After or RateLimit-Reset, and retries
+// transient 5xx errors with exponential backoff and jitter, up to
+// maxTransientRetries retries in all.
+func (c *Client) doRequest(ctx context.Context, base, method, endpoint string, body interface{}, authenticated bool) ([]byte, error) {
+	bearer := ""
+	if authenticated {
+		bearer = c.accessJwt
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		if status := c.rateLimit; status.Remaining == 0 && !status.Reset.IsZero() {
+			if wait := time.Until(status.Reset); wait > 0 {
+				fmt.Printf("\npausing %s for rate limit\n", wait.Round(time.Second))
+				if err := sleepCtx(ctx, wait); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		respBody, err := c.doRequestWithAuth(ctx, base, method, endpoint, body, bearer)
+		if err == nil {
+			return respBody, nil
+		}
+
+		var expiredErr *expiredTokenError
+		if authenticated && !refreshed && errors.As(err, &expiredErr) {
+			if refreshErr := c.RefreshSession(ctx); refreshErr != nil {
+				return nil, fmt.Errorf("%w (refresh failed: %v)", err, refreshErr)
+			}
+			bearer = c.accessJwt
+			refreshed = true
+			continue
+		}
+
+		var rateLimitErr *rateLimitedError
+		if errors.As(err, &rateLimitErr) && attempt < maxTransientRetries {
+			fmt.Printf("\npausing %s for rate limit\n", rateLimitErr.wait.Round(time.Second))
+			if sleepErr := sleepCtx(ctx, rateLimitErr.wait); sleepErr != nil {
+				return nil, sleepErr
+			}
+			continue
+		}
+
+		var transientErr *transientServerError
+		if errors.As(err, &transientErr) && attempt < maxTransientRetries {
+			if sleepErr := sleepCtx(ctx, backoffWithJitter(attempt)); sleepErr != nil {
+				return nil, sleepErr
+			}
+			continue
+		}
+
+		return nil, err
+	}
+}
+
+// RefreshSession exchanges the stored refresh token for a new access/refresh
+// token pair via com.atproto.server.refreshSession. doRequest calls this
+// automatically on an ExpiredToken response.
+func (c *Client) RefreshSession(ctx context.Context) error {
+	if c.refreshJwt == "" {
+		return fmt.Errorf("refresh session: no refresh token available")
+	}
+
+	respBody, err := c.doRequestWithAuth(ctx, c.pdsBase, "POST", "/com.atproto.server.refreshSession", nil, c.refreshJwt)
+	if err != nil {
+		return fmt.Errorf("refresh session: %w", err)
+	}
+
+	var resp refreshSessionResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("parse refresh session response: %w", err)
+	}
+
+	c.accessJwt = resp.AccessJwt
+	c.refreshJwt = resp.RefreshJwt
+	c.did = resp.DID
+	return nil
+}
+
+func (c *Client) CreateSession(ctx context.Context, handle, password string) error {
 	reqBody := createSessionRequest{
 		Identifier: handle,
 		Password:   password,
 	}
 
-	respBody, err := c.doRequest("POST", "/com.atproto.server.createSession", reqBody, false)
+	respBody, err := c.doRequest(ctx, c.pdsBase, "POST", "/com.atproto.server.createSession", reqBody, false)
 	if err != nil {
 		return fmt.Errorf("create session: %w", err)
 	}
@@ -145,21 +531,31 @@ func (c *Client) CreateSession(handle, password string) error {
 	}
 
 	c.accessJwt = resp.AccessJwt
+	c.refreshJwt = resp.RefreshJwt
 	c.did = resp.DID
 	return nil
 }
 
-func (c *Client) GetFollows(handle string) ([]profileView, error) {
+// GetFollows fetches handle's follows from base, the host that should serve
+// this app.bsky.graph.getFollows query — typically the target's own PDS, or
+// publicAppView for accounts indexed by Bluesky's AppView. It's a public
+// read, so it's never sent with the authenticated user's access token —
+// base may be a third-party host outside that token's audience.
+func (c *Client) GetFollows(ctx context.Context, base, handle string) ([]profileView, error) {
 	var allFollows []profileView
 	cursor := ""
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		endpoint := fmt.Sprintf("/app.bsky.graph.getFollows?actor=%s&limit=100", url.QueryEscape(handle))
 		if cursor != "" {
 			endpoint += "&cursor=" + url.QueryEscape(cursor)
 		}
 
-		respBody, err := c.doRequest("GET", endpoint, nil, true)
+		respBody, err := c.doRequest(ctx, base, "GET", endpoint, nil, false)
 		if err != nil {
 			return nil, fmt.Errorf("get follows: %w", err)
 		}
@@ -182,7 +578,7 @@ func (c *Client) GetFollows(handle string) ([]profileView, error) {
 	return allFollows, nil
 }
 
-func (c *Client) CreateList(name string) (string, error) {
+func (c *Client) CreateList(ctx context.Context, name string) (string, error) {
 	record := listRecord{
 		Type:        "app.bsky.graph.list",
 		Purpose:     "app.bsky.graph.defs#curatelist",
@@ -197,7 +593,7 @@ func (c *Client) CreateList(name string) (string, error) {
 		Record:     record,
 	}
 
-	respBody, err := c.doRequest("POST", "/com.atproto.repo.createRecord", reqBody, true)
+	respBody, err := c.doRequest(ctx, c.pdsBase, "POST", "/com.atproto.repo.createRecord", reqBody, true)
 	if err != nil {
 		return "", fmt.Errorf("create list: %w", err)
 	}
@@ -210,7 +606,123 @@ func (c *Client) CreateList(name string) (string, error) {
 	return resp.URI, nil
 }
 
-func (c *Client) AddListItem(listURI, subjectDID string) error {
+// FindList looks up a list owned by ownerDID by name via
+// app.bsky.graph.getLists, paginating until it finds a match or runs out of
+// lists. found is false if no list with that name exists.
+func (c *Client) FindList(ctx context.Context, ownerDID, name string) (uri string, found bool, err error) {
+	cursor := ""
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+
+		endpoint := fmt.Sprintf("/app.bsky.graph.getLists?actor=%s&limit=100", url.QueryEscape(ownerDID))
+		if cursor != "" {
+			endpoint += "&cursor=" + url.QueryEscape(cursor)
+		}
+
+		respBody, err := c.doRequest(ctx, c.pdsBase, "GET", endpoint, nil, true)
+		if err != nil {
+			return "", false, fmt.Errorf("find list: %w", err)
+		}
+
+		var resp getListsResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return "", false, fmt.Errorf("parse get lists response: %w", err)
+		}
+
+		for _, l := range resp.Lists {
+			if l.Name == name {
+				return l.URI, true, nil
+			}
+		}
+
+		if resp.Cursor == "" {
+			break
+		}
+		cursor = resp.Cursor
+	}
+
+	return "", false, nil
+}
+
+// GetListItems fetches the current members of a list via
+// app.bsky.graph.getList and returns a map of subject DID to the listitem
+// record's rkey, so callers can diff membership and issue targeted deletes.
+func (c *Client) GetListItems(ctx context.Context, listURI string) (map[string]string, error) {
+	members := make(map[string]string)
+	cursor := ""
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		endpoint := fmt.Sprintf("/app.bsky.graph.getList?list=%s&limit=100", url.QueryEscape(listURI))
+		if cursor != "" {
+			endpoint += "&cursor=" + url.QueryEscape(cursor)
+		}
+
+		respBody, err := c.doRequest(ctx, c.pdsBase, "GET", endpoint, nil, true)
+		if err != nil {
+			return nil, fmt.Errorf("get list items: %w", err)
+		}
+
+		var resp getListResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return nil, fmt.Errorf("parse get list response: %w", err)
+		}
+
+		for _, item := range resp.Items {
+			rkey, err := rkeyFromURI(item.URI)
+			if err != nil {
+				return nil, err
+			}
+			members[item.Subject.DID] = rkey
+		}
+
+		if resp.Cursor == "" {
+			break
+		}
+		cursor = resp.Cursor
+	}
+
+	return members, nil
+}
+
+// rkeyFromURI extracts the record key from an at:// record URI
+// (at://did/collection/rkey).
+func rkeyFromURI(uri string) (string, error) {
+	parts := strings.Split(uri, "/")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("invalid record uri: %s", uri)
+	}
+	return parts[len(parts)-1], nil
+}
+
+// ApplyWrites performs a batch of up to 200 create/update/delete operations
+// in a single com.atproto.repo.applyWrites call. The call is transactional:
+// either every write in the batch is applied, or none are and err is
+// non-nil. There is no per-write success/failure to report back, since the
+// API doesn't expose one.
+func (c *Client) ApplyWrites(ctx context.Context, writes []Write) error {
+	reqBody := applyWritesRequest{
+		Repo:   c.did,
+		Writes: writes,
+	}
+
+	if _, err := c.doRequest(ctx, c.pdsBase, "POST", "/com.atproto.repo.applyWrites", reqBody, true); err != nil {
+		return fmt.Errorf("apply writes: %w", err)
+	}
+
+	return nil
+}
+
+// AddListItem adds a single member to a list. It's a thin wrapper around
+// ApplyWrites kept for callers that only need to add one item at a time;
+// bulk callers should batch through ApplyWrites directly.
+func (c *Client) AddListItem(ctx context.Context, listURI, subjectDID string) error {
 	record := listItemRecord{
 		Type:      "app.bsky.graph.listitem",
 		Subject:   subjectDID,
@@ -218,14 +730,11 @@ func (c *Client) AddListItem(listURI, subjectDID string) error {
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
-	reqBody := createRecordRequest{
-		Repo:       c.did,
+	if err := c.ApplyWrites(ctx, []Write{{
+		Op:         WriteCreate,
 		Collection: "app.bsky.graph.listitem",
-		Record:     record,
-	}
-
-	_, err := c.doRequest("POST", "/com.atproto.repo.createRecord", reqBody, true)
-	if err != nil {
+		Value:      record,
+	}}); err != nil {
 		return fmt.Errorf("add list item: %w", err)
 	}
 
@@ -242,12 +751,17 @@ Arguments:
   handle         Bluesky handle to copy follows from (e.g., user.bsky.social)
 
 Flags:
-  --name, -n     Custom name for the list (required)
-  --help, -h     Show this help message
+  --name, -n       Custom name for the list (required)
+  --batch-size     Number of members written per applyWrites call (default 200)
+  --sync, --update Update an existing list instead of always creating a new one
+  --dry-run        Print the planned add/remove counts without changing anything
+  --pds            xrpc base URL of your account's PDS (default https://bsky.social/xrpc)
+  --help, -h       Show this help message
 
 Environment variables:
   BSKY_HANDLE    Your Bluesky handle
   BSKY_APP_KEY   Your app password (Settings > App Passwords)
+  BSKY_PDS       xrpc base URL of your account's PDS, same as --pds
 
 Example:
   BSKY_HANDLE=me.bsky.social BSKY_APP_KEY=xxxx bsky-spy --name "Tech Folks" techperson.bsky.social
@@ -258,7 +772,9 @@ Example:
 func main() {
 	// Parse arguments
 	args := os.Args[1:]
-	var listName, targetHandle string
+	var listName, targetHandle, pds string
+	batchSize := defaultBatchSize
+	var syncMode, dryRun bool
 
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
@@ -276,6 +792,38 @@ func main() {
 			listName = strings.TrimPrefix(arg, "--name=")
 		case strings.HasPrefix(arg, "-n="):
 			listName = strings.TrimPrefix(arg, "-n=")
+		case arg == "--batch-size":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --batch-size requires a value")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n <= 0 || n > 200 {
+				fmt.Fprintln(os.Stderr, "Error: --batch-size must be an integer between 1 and 200")
+				os.Exit(1)
+			}
+			batchSize = n
+		case strings.HasPrefix(arg, "--batch-size="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--batch-size="))
+			if err != nil || n <= 0 || n > 200 {
+				fmt.Fprintln(os.Stderr, "Error: --batch-size must be an integer between 1 and 200")
+				os.Exit(1)
+			}
+			batchSize = n
+		case arg == "--sync" || arg == "--update":
+			syncMode = true
+		case arg == "--dry-run":
+			dryRun = true
+		case arg == "--pds":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --pds requires a value")
+				os.Exit(1)
+			}
+			i++
+			pds = args[i]
+		case strings.HasPrefix(arg, "--pds="):
+			pds = strings.TrimPrefix(arg, "--pds=")
 		case !strings.HasPrefix(arg, "-"):
 			if targetHandle == "" {
 				targetHandle = arg
@@ -308,18 +856,37 @@ func main() {
 		os.Exit(1)
 	}
 
-	client := NewClient()
+	if pds == "" {
+		pds = os.Getenv("BSKY_PDS")
+	}
+	if pds == "" {
+		pds = defaultPDS
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := NewClient(pds)
 
 	// Authenticate
 	fmt.Println("Authenticating...")
-	if err := client.CreateSession(handle, appKey); err != nil {
+	if err := client.CreateSession(ctx, handle, appKey); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Discover where to read the target's follows from: their own PDS if we
+	// can resolve it, otherwise fall back to the public AppView.
+	followsBase := publicAppView
+	if targetDID, err := client.ResolveHandle(ctx, targetHandle); err == nil {
+		if pdsEndpoint, err := client.ResolvePDSEndpoint(ctx, targetDID); err == nil {
+			followsBase = pdsEndpoint
+		}
+	}
+
 	// Fetch follows
 	fmt.Printf("Fetching follows for %s...\n", targetHandle)
-	follows, err := client.GetFollows(targetHandle)
+	follows, err := client.GetFollows(ctx, followsBase, targetHandle)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -332,28 +899,153 @@ func main() {
 
 	fmt.Printf("Found %d follows\n", len(follows))
 
-	// Create list
-	fmt.Printf("Creating list \"%s\"...\n", listName)
-	listURI, err := client.CreateList(listName)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	// Look up an existing list to sync against, if requested
+	var listURI string
+	var existing map[string]string
+	if syncMode {
+		fmt.Printf("Looking for existing list \"%s\"...\n", listName)
+		uri, found, err := client.FindList(ctx, client.DID(), listName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if found {
+			listURI = uri
+			fmt.Println("Found existing list, fetching current members...")
+			existing, err = client.GetListItems(ctx, listURI)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Println("No existing list found, a new one will be created.")
+		}
+	}
+
+	// Diff the target's follows against existing membership (empty for a
+	// brand new list, so every follow becomes a create). The list URI
+	// doesn't affect the counts, so this preview is safe to run before the
+	// list exists; it's replanned below with the real URI before any writes
+	// are sent.
+	now := time.Now().UTC().Format(time.RFC3339)
+	preview, _ := planListChanges(follows, existing, "", now)
+
+	if len(preview) == 0 {
+		fmt.Println("List is already in sync; no changes needed.")
+		os.Exit(0)
 	}
 
-	// Add members
-	fmt.Println("Adding members to list...")
-	for i, follow := range follows {
-		if err := client.AddListItem(listURI, follow.DID); err != nil {
-			fmt.Fprintf(os.Stderr, "\nWarning: failed to add %s: %v\n", follow.Handle, err)
+	if dryRun {
+		toAdd, toRemove := 0, 0
+		for _, w := range preview {
+			if w.Op == WriteDelete {
+				toRemove++
+			} else {
+				toAdd++
+			}
+		}
+		fmt.Printf("Dry run: would add %d and remove %d member(s) from list \"%s\".\n", toAdd, toRemove, listName)
+		os.Exit(0)
+	}
+
+	if listURI == "" {
+		fmt.Printf("Creating list \"%s\"...\n", listName)
+		uri, err := client.CreateList(ctx, listName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		listURI = uri
+	}
+
+	// Replan with the real list URI now that it's guaranteed to exist, so
+	// every create write is stamped with the correct list.
+	writes, descriptions := planListChanges(follows, existing, listURI, now)
+
+	// Apply the diff in batches via applyWrites
+	fmt.Println("Applying changes to list...")
+	added, removed := applyListChanges(ctx, client, writes, descriptions, batchSize)
+
+	fmt.Printf("Done! List \"%s\": %d member(s) added, %d removed.\n", listName, added, removed)
+	fmt.Printf("View at: https://bsky.app/profile/%s/lists\n", handle)
+}
+
+// planListChanges diffs the target's current follows against an existing
+// list's membership (existing is nil for a brand new list) and returns the
+// minimal set of applyWrites creates and deletes needed to reconcile them,
+// along with a human-readable description of each for error reporting.
+func planListChanges(follows []profileView, existing map[string]string, listURI, createdAt string) ([]Write, []string) {
+	var writes []Write
+	var descriptions []string
+
+	followedDIDs := make(map[string]bool, len(follows))
+	for _, follow := range follows {
+		followedDIDs[follow.DID] = true
+		if _, ok := existing[follow.DID]; ok {
 			continue
 		}
-		fmt.Printf("\r  Added %d/%d members...", i+1, len(follows))
+		writes = append(writes, Write{
+			Op:         WriteCreate,
+			Collection: "app.bsky.graph.listitem",
+			Value: listItemRecord{
+				Type:      "app.bsky.graph.listitem",
+				Subject:   follow.DID,
+				List:      listURI,
+				CreatedAt: createdAt,
+			},
+		})
+		descriptions = append(descriptions, "add "+follow.Handle)
+	}
 
-		// Rate limiting delay
-		time.Sleep(50 * time.Millisecond)
+	for did, rkey := range existing {
+		if followedDIDs[did] {
+			continue
+		}
+		writes = append(writes, Write{
+			Op:         WriteDelete,
+			Collection: "app.bsky.graph.listitem",
+			RKey:       rkey,
+		})
+		descriptions = append(descriptions, "remove "+did)
+	}
+
+	return writes, descriptions
+}
+
+// applyListChanges runs writes through ApplyWrites in batches of batchSize.
+// Each batch is all-or-nothing, so a failed batch is reported as a single
+// warning and none of its writes count toward the returned totals.
+func applyListChanges(ctx context.Context, client *Client, writes []Write, descriptions []string, batchSize int) (added, removed int) {
+	for start := 0; start < len(writes); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "\nInterrupted: %v\n", err)
+			os.Exit(1)
+		}
+
+		end := start + batchSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+		batchWrites := writes[start:end]
+		batchDesc := descriptions[start:end]
+
+		// applyWrites is transactional: the whole batch lands or none of it
+		// does, so a failure here means none of batchDesc took effect.
+		if err := client.ApplyWrites(ctx, batchWrites); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: batch of %d writes failed, none applied (%s .. %s): %v\n",
+				len(batchWrites), batchDesc[0], batchDesc[len(batchDesc)-1], err)
+			continue
+		}
+		for _, w := range batchWrites {
+			if w.Op == WriteDelete {
+				removed++
+			} else {
+				added++
+			}
+		}
+		fmt.Printf("\r  Applied %d/%d changes...", added+removed, len(writes))
 	}
 	fmt.Println()
 
-	fmt.Printf("Done! List \"%s\" created with %d members.\n", listName, len(follows))
-	fmt.Printf("View at: https://bsky.app/profile/%s/lists\n", handle)
+	return added, removed
 }
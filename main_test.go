@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPlanListChangesNewList(t *testing.T) {
+	follows := []profileView{
+		{DID: "did:plc:alice", Handle: "alice.bsky.social"},
+		{DID: "did:plc:bob", Handle: "bob.bsky.social"},
+	}
+
+	writes, descriptions := planListChanges(follows, nil, "at://did:plc:me/app.bsky.graph.list/abc", "2026-01-01T00:00:00Z")
+
+	if len(writes) != 2 || len(descriptions) != 2 {
+		t.Fatalf("got %d writes and %d descriptions, want 2 and 2", len(writes), len(descriptions))
+	}
+	for _, w := range writes {
+		if w.Op != WriteCreate {
+			t.Errorf("write op = %q, want %q", w.Op, WriteCreate)
+		}
+		record, ok := w.Value.(listItemRecord)
+		if !ok {
+			t.Fatalf("write value is %T, want listItemRecord", w.Value)
+		}
+		if record.List != "at://did:plc:me/app.bsky.graph.list/abc" {
+			t.Errorf("record.List = %q, want the real list URI, not empty", record.List)
+		}
+	}
+}
+
+func TestPlanListChangesSyncAddsAndRemoves(t *testing.T) {
+	follows := []profileView{
+		{DID: "did:plc:alice", Handle: "alice.bsky.social"}, // already a member
+		{DID: "did:plc:carol", Handle: "carol.bsky.social"}, // needs adding
+	}
+	existing := map[string]string{
+		"did:plc:alice": "rkey-alice",
+		"did:plc:bob":   "rkey-bob", // unfollowed, needs removing
+	}
+
+	writes, descriptions := planListChanges(follows, existing, "at://did:plc:me/app.bsky.graph.list/abc", "2026-01-01T00:00:00Z")
+
+	var creates, deletes int
+	for i, w := range writes {
+		switch w.Op {
+		case WriteCreate:
+			creates++
+			record := w.Value.(listItemRecord)
+			if record.Subject != "did:plc:carol" {
+				t.Errorf("unexpected create for subject %q", record.Subject)
+			}
+		case WriteDelete:
+			deletes++
+			if w.RKey != "rkey-bob" {
+				t.Errorf("delete RKey = %q, want %q", w.RKey, "rkey-bob")
+			}
+			if descriptions[i] != "remove did:plc:bob" {
+				t.Errorf("description = %q, want %q", descriptions[i], "remove did:plc:bob")
+			}
+		default:
+			t.Errorf("unexpected op %q", w.Op)
+		}
+	}
+	if creates != 1 || deletes != 1 {
+		t.Fatalf("got %d creates and %d deletes, want 1 and 1", creates, deletes)
+	}
+}
+
+func TestPlanListChangesNoOp(t *testing.T) {
+	follows := []profileView{{DID: "did:plc:alice", Handle: "alice.bsky.social"}}
+	existing := map[string]string{"did:plc:alice": "rkey-alice"}
+
+	writes, descriptions := planListChanges(follows, existing, "at://did:plc:me/app.bsky.graph.list/abc", "2026-01-01T00:00:00Z")
+
+	if len(writes) != 0 || len(descriptions) != 0 {
+		t.Fatalf("got %d writes and %d descriptions, want 0 and 0", len(writes), len(descriptions))
+	}
+}
+
+func TestWriteMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		w    Write
+		want map[string]interface{}
+	}{
+		{
+			name: "create",
+			w: Write{
+				Op:         WriteCreate,
+				Collection: "app.bsky.graph.listitem",
+				Value:      listItemRecord{Type: "app.bsky.graph.listitem", Subject: "did:plc:alice"},
+			},
+			want: map[string]interface{}{
+				"$type":      "com.atproto.repo.applyWrites#create",
+				"collection": "app.bsky.graph.listitem",
+				"value": map[string]interface{}{
+					"$type":     "app.bsky.graph.listitem",
+					"subject":   "did:plc:alice",
+					"list":      "",
+					"createdAt": "",
+				},
+			},
+		},
+		{
+			name: "delete",
+			w:    Write{Op: WriteDelete, Collection: "app.bsky.graph.listitem", RKey: "rkey-1"},
+			want: map[string]interface{}{
+				"$type":      "com.atproto.repo.applyWrites#delete",
+				"collection": "app.bsky.graph.listitem",
+				"rkey":       "rkey-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.w)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("got %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestWriteMarshalJSONUnknownOp(t *testing.T) {
+	_, err := json.Marshal(Write{Op: "bogus"})
+	if err == nil {
+		t.Fatal("expected an error for an unknown op, got nil")
+	}
+}
+
+func TestRkeyFromURI(t *testing.T) {
+	rkey, err := rkeyFromURI("at://did:plc:alice/app.bsky.graph.listitem/3jzfcijpj2z2a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rkey != "3jzfcijpj2z2a" {
+		t.Errorf("rkey = %q, want %q", rkey, "3jzfcijpj2z2a")
+	}
+}
+
+func TestRkeyFromURIInvalid(t *testing.T) {
+	if _, err := rkeyFromURI("not-a-uri"); err == nil {
+		t.Error("expected an error for a URI with no path segments, got nil")
+	}
+}
+
+func TestRetryWaitPrefersRetryAfterHeader(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"3"}}
+	got := retryWait(header, RateLimitStatus{})
+	if got != 3*time.Second {
+		t.Errorf("retryWait = %v, want %v", got, 3*time.Second)
+	}
+}
+
+func TestRetryWaitFallsBackToRateLimitReset(t *testing.T) {
+	reset := time.Now().Add(10 * time.Second)
+	got := retryWait(http.Header{}, RateLimitStatus{Reset: reset})
+	if got <= 0 || got > 10*time.Second {
+		t.Errorf("retryWait = %v, want a positive duration up to 10s", got)
+	}
+}
+
+func TestRetryWaitDefaultsToOneSecond(t *testing.T) {
+	got := retryWait(http.Header{}, RateLimitStatus{})
+	if got != time.Second {
+		t.Errorf("retryWait = %v, want %v", got, time.Second)
+	}
+}
+
+func TestBackoffWithJitterGrowsAndStaysBounded(t *testing.T) {
+	var last time.Duration
+	for attempt := 0; attempt < 5; attempt++ {
+		d := backoffWithJitter(attempt)
+		base := 500 * time.Millisecond * time.Duration(1<<attempt)
+		if d < base || d > base+base/2 {
+			t.Errorf("attempt %d: backoffWithJitter = %v, want within [%v, %v]", attempt, d, base, base+base/2)
+		}
+		if d < last {
+			t.Errorf("attempt %d: backoff %v is smaller than previous attempt's minimum %v", attempt, d, last)
+		}
+		last = base
+	}
+}